@@ -0,0 +1,217 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptiveConcurrencyConfig tunes the AIMD controller installed by
+// WithAdaptiveConcurrency: the in-flight limit grows by one after a
+// streak of healthy requests, and is cut multiplicatively the moment
+// requests start coming back throttled or slow.
+type AdaptiveConcurrencyConfig struct {
+	// MinLimit and MaxLimit bound the in-flight limit.
+	MinLimit, MaxLimit int
+	// InitialLimit is the in-flight limit new clients start with.
+	InitialLimit int
+	// SuccessesBeforeIncrease is the length of the healthy streak
+	// required before the limit is increased by one.
+	SuccessesBeforeIncrease int
+	// DecreaseFactor is multiplied into the limit, in (0, 1), whenever a
+	// request is throttled or the window's p95 latency crosses
+	// LatencyThreshold.
+	DecreaseFactor float64
+	// LatencyThreshold is the p95 latency, over the last SampleWindow
+	// requests, above which the controller treats the backend as
+	// degraded even without an explicit throttling response.
+	LatencyThreshold time.Duration
+	// SampleWindow is how many recent request latencies are kept to
+	// estimate p95 latency.
+	SampleWindow int
+}
+
+// DefaultAdaptiveConcurrencyConfig returns the defaults used by
+// WithAdaptiveConcurrency.
+func DefaultAdaptiveConcurrencyConfig() AdaptiveConcurrencyConfig {
+	return AdaptiveConcurrencyConfig{
+		MinLimit:                1,
+		MaxLimit:                256,
+		InitialLimit:            16,
+		SuccessesBeforeIncrease: 10,
+		DecreaseFactor:          0.5,
+		LatencyThreshold:        2 * time.Second,
+		SampleWindow:            50,
+	}
+}
+
+func (cfg AdaptiveConcurrencyConfig) withDefaults() AdaptiveConcurrencyConfig {
+	def := DefaultAdaptiveConcurrencyConfig()
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = def.MinLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = def.MaxLimit
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = def.InitialLimit
+	}
+	if cfg.SuccessesBeforeIncrease <= 0 {
+		cfg.SuccessesBeforeIncrease = def.SuccessesBeforeIncrease
+	}
+	if cfg.DecreaseFactor <= 0 || cfg.DecreaseFactor >= 1 {
+		cfg.DecreaseFactor = def.DecreaseFactor
+	}
+	if cfg.LatencyThreshold <= 0 {
+		cfg.LatencyThreshold = def.LatencyThreshold
+	}
+	if cfg.SampleWindow <= 0 {
+		cfg.SampleWindow = def.SampleWindow
+	}
+	return cfg
+}
+
+// WithAdaptiveConcurrency wraps the client's transport with an in-flight
+// limit that grows additively on healthy streaks and shrinks
+// multiplicatively the moment requests come back throttled (429/503, or
+// carrying Retry-After) or the observed p95 latency degrades, using
+// DefaultAdaptiveConcurrencyConfig.
+func WithAdaptiveConcurrency() Option {
+	return WithAdaptiveConcurrencyConfig(DefaultAdaptiveConcurrencyConfig())
+}
+
+// WithAdaptiveConcurrencyConfig is WithAdaptiveConcurrency with a custom
+// AdaptiveConcurrencyConfig. If WithRetryPolicy is also applied, the
+// controller always ends up wrapped by the retry loop rather than
+// wrapping it, regardless of application order, so every retry attempt
+// acquires its own slot and feeds the AIMD controller instead of one
+// slot and one outcome covering a whole logical call.
+func WithAdaptiveConcurrencyConfig(cfg AdaptiveConcurrencyConfig) Option {
+	return func(c *http.Client) {
+		limiter := newAdaptiveLimiter(cfg.withDefaults())
+		wrapTransport(c, func(next http.RoundTripper) http.RoundTripper {
+			return &adaptiveConcurrencyTransport{next: next, limiter: limiter}
+		})
+	}
+}
+
+type adaptiveConcurrencyTransport struct {
+	next    http.RoundTripper
+	limiter *adaptiveLimiter
+}
+
+func (t *adaptiveConcurrencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+	t.limiter.release()
+
+	throttled := err == nil && (resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusServiceUnavailable || retryAfter(resp) > 0)
+	t.limiter.recordOutcome(elapsed, throttled)
+
+	return resp, err
+}
+
+// adaptiveLimiter is the AIMD controller behind WithAdaptiveConcurrency:
+// a resizable semaphore whose limit is adjusted by recordOutcome.
+type adaptiveLimiter struct {
+	cfg AdaptiveConcurrencyConfig
+
+	mu         sync.Mutex
+	limit      int
+	inFlight   int
+	successRun int
+	latencies  []time.Duration
+	released   chan struct{}
+}
+
+func newAdaptiveLimiter(cfg AdaptiveConcurrencyConfig) *adaptiveLimiter {
+	return &adaptiveLimiter{
+		cfg:      cfg,
+		limit:    cfg.InitialLimit,
+		released: make(chan struct{}),
+	}
+}
+
+// acquire blocks until a slot is free or ctx is done.
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// release frees a slot and wakes any acquire waiting on one.
+func (l *adaptiveLimiter) release() {
+	l.mu.Lock()
+	l.inFlight--
+	wake := l.released
+	l.released = make(chan struct{})
+	l.mu.Unlock()
+	close(wake)
+}
+
+// recordOutcome adjusts the limit: multiplicative decrease the moment a
+// request is throttled or p95 latency degrades, additive increase after
+// SuccessesBeforeIncrease consecutive healthy requests.
+func (l *adaptiveLimiter) recordOutcome(latency time.Duration, throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !throttled {
+		l.latencies = append(l.latencies, latency)
+		if len(l.latencies) > l.cfg.SampleWindow {
+			l.latencies = l.latencies[len(l.latencies)-l.cfg.SampleWindow:]
+		}
+		throttled = percentile95(l.latencies) > l.cfg.LatencyThreshold
+	}
+
+	if throttled {
+		l.successRun = 0
+		l.latencies = l.latencies[:0]
+		l.limit = max(l.cfg.MinLimit, int(float64(l.limit)*l.cfg.DecreaseFactor))
+		return
+	}
+
+	l.successRun++
+	if l.successRun >= l.cfg.SuccessesBeforeIncrease {
+		l.successRun = 0
+		if l.limit < l.cfg.MaxLimit {
+			l.limit++
+		}
+	}
+}
+
+func percentile95(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(float64(len(sorted)) * 0.95)
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
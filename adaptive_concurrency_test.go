@@ -0,0 +1,273 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grows the limit after a streak of healthy requests", func(t *testing.T) {
+		t.Parallel()
+
+		l := newAdaptiveLimiter(AdaptiveConcurrencyConfig{
+			MinLimit:                1,
+			MaxLimit:                10,
+			InitialLimit:            2,
+			SuccessesBeforeIncrease: 3,
+			DecreaseFactor:          0.5,
+			LatencyThreshold:        time.Second,
+			SampleWindow:            50,
+		})
+
+		for i := 0; i < 3; i++ {
+			l.recordOutcome(time.Millisecond, false)
+		}
+		assert.Equal(t, 3, l.limit)
+	})
+
+	t.Run("shrinks the limit the moment a request is throttled", func(t *testing.T) {
+		t.Parallel()
+
+		l := newAdaptiveLimiter(AdaptiveConcurrencyConfig{
+			MinLimit:                1,
+			MaxLimit:                10,
+			InitialLimit:            8,
+			SuccessesBeforeIncrease: 3,
+			DecreaseFactor:          0.5,
+			LatencyThreshold:        time.Second,
+			SampleWindow:            50,
+		})
+
+		l.recordOutcome(time.Millisecond, true)
+		assert.Equal(t, 4, l.limit)
+	})
+
+	t.Run("shrinks the limit when p95 latency degrades without explicit throttling", func(t *testing.T) {
+		t.Parallel()
+
+		l := newAdaptiveLimiter(AdaptiveConcurrencyConfig{
+			MinLimit:                1,
+			MaxLimit:                10,
+			InitialLimit:            8,
+			SuccessesBeforeIncrease: 1000,
+			DecreaseFactor:          0.5,
+			LatencyThreshold:        10 * time.Millisecond,
+			SampleWindow:            5,
+		})
+
+		for i := 0; i < 4; i++ {
+			l.recordOutcome(time.Millisecond, false)
+		}
+		require.Equal(t, 8, l.limit, "four fast requests shouldn't trip the latency guard")
+
+		l.recordOutcome(100*time.Millisecond, false)
+		assert.Equal(t, 4, l.limit, "a slow request pushing p95 past the threshold trips it once")
+	})
+
+	t.Run("acquire blocks at the limit and release wakes a waiter", func(t *testing.T) {
+		t.Parallel()
+
+		l := newAdaptiveLimiter(AdaptiveConcurrencyConfig{
+			MinLimit: 1, MaxLimit: 10, InitialLimit: 1,
+			SuccessesBeforeIncrease: 3, DecreaseFactor: 0.5,
+			LatencyThreshold: time.Second, SampleWindow: 50,
+		})
+
+		require.NoError(t, l.acquire(context.Background()))
+
+		done := make(chan struct{})
+		go func() {
+			_ = l.acquire(context.Background())
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("acquire should block while the single slot is held")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		l.release()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("acquire should unblock after release")
+		}
+	})
+
+	t.Run("acquire returns when the context is cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		l := newAdaptiveLimiter(AdaptiveConcurrencyConfig{
+			MinLimit: 1, MaxLimit: 10, InitialLimit: 1,
+			SuccessesBeforeIncrease: 3, DecreaseFactor: 0.5,
+			LatencyThreshold: time.Second, SampleWindow: 50,
+		})
+		require.NoError(t, l.acquire(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := l.acquire(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithAdaptiveConcurrencyConfig shrinks the limit after throttled responses", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := New(WithAdaptiveConcurrencyConfig(AdaptiveConcurrencyConfig{
+			MinLimit:                1,
+			MaxLimit:                10,
+			InitialLimit:            8,
+			SuccessesBeforeIncrease: 3,
+			DecreaseFactor:          0.5,
+			LatencyThreshold:        time.Second,
+			SampleWindow:            50,
+		}))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		limiter := client.Transport.(*adaptiveConcurrencyTransport).limiter
+		limiter.mu.Lock()
+		limit := limiter.limit
+		limiter.mu.Unlock()
+		assert.Equal(t, 4, limit, "a 429 must trip the AIMD controller's multiplicative decrease")
+	})
+
+	t.Run("WithAdaptiveConcurrencyConfig bounds concurrent requests at the limit", func(t *testing.T) {
+		t.Parallel()
+
+		block := make(chan struct{})
+		var inFlight int32
+		var peak int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-block
+			atomic.AddInt32(&inFlight, -1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithAdaptiveConcurrencyConfig(AdaptiveConcurrencyConfig{
+			MinLimit:                1,
+			MaxLimit:                10,
+			InitialLimit:            2,
+			SuccessesBeforeIncrease: 3,
+			DecreaseFactor:          0.5,
+			LatencyThreshold:        time.Second,
+			SampleWindow:            50,
+		}))
+
+		done := make(chan struct{})
+		for i := 0; i < 5; i++ {
+			go func() {
+				req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+				if err != nil {
+					done <- struct{}{}
+					return
+				}
+				resp, doErr := client.Do(req)
+				if doErr == nil {
+					resp.Body.Close()
+				}
+				done <- struct{}{}
+			}()
+		}
+
+		time.Sleep(20 * time.Millisecond) // let all requests that can start do so
+		close(block)
+		for i := 0; i < 5; i++ {
+			<-done
+		}
+
+		assert.LessOrEqual(t, int(atomic.LoadInt32(&peak)), 2)
+	})
+
+	for _, order := range []string{"retry-then-adaptive", "adaptive-then-retry"} {
+		order := order
+		t.Run("every retry attempt acquires its own slot, "+order, func(t *testing.T) {
+			t.Parallel()
+
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = 3
+			policy.BaseDelay = time.Millisecond
+			policy.MaxDelay = time.Millisecond
+
+			adaptive := WithAdaptiveConcurrencyConfig(AdaptiveConcurrencyConfig{
+				MinLimit:                1,
+				MaxLimit:                10,
+				InitialLimit:            8,
+				SuccessesBeforeIncrease: 3,
+				DecreaseFactor:          0.5,
+				LatencyThreshold:        time.Second,
+				SampleWindow:            50,
+			})
+
+			var client *http.Client
+			if order == "retry-then-adaptive" {
+				client = New(WithRetryPolicy(policy), adaptive)
+			} else {
+				client = New(adaptive, WithRetryPolicy(policy))
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			_, doErr := client.Do(req)
+			require.Error(t, doErr, "429 is retryable, so the policy exhausts all attempts and surfaces an error")
+
+			assert.EqualValues(t, 3, hits)
+
+			limiter := client.Transport.(*retryTransport).next.(*adaptiveConcurrencyTransport).limiter
+			limiter.mu.Lock()
+			limit := limiter.limit
+			limiter.mu.Unlock()
+			assert.Equal(t, 1, limit, "each of the 3 throttled attempts must be recorded by the controller, not just one per client.Do")
+		})
+	}
+}
+
+func TestPercentile95(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Duration(0), percentile95(nil))
+
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		samples[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 96*time.Millisecond, percentile95(samples))
+}
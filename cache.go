@@ -0,0 +1,329 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStore persists serialized HTTP responses for WithCache. Get/Set/
+// Delete operate on an opaque key WithCache derives from the request
+// method, URL, and any headers named in a cached response's Vary.
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, resp []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+// WithCache wraps the client's transport with an HTTP cache honoring
+// RFC 9111: only GET/HEAD requests with cacheable, non-private,
+// non-no-store responses are stored; freshness is computed from
+// Cache-Control's s-maxage/max-age (or Expires); a stale entry carrying a
+// validator is revalidated with If-None-Match/If-Modified-Since,
+// upgrading a 304 response to the cached body; entries within their
+// stale-while-revalidate window are served immediately while a
+// background request refreshes the cache.
+func WithCache(store CacheStore) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &cacheTransport{next: next, store: store}
+	})
+}
+
+type cacheTransport struct {
+	next  http.RoundTripper
+	store CacheStore
+}
+
+func (ct *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return ct.next.RoundTrip(req)
+	}
+	if hasDirective(req.Header.Get("Cache-Control"), "no-store") {
+		return ct.next.RoundTrip(req)
+	}
+
+	base := cacheRequestKey(req)
+	key := ct.variantKey(base, req)
+
+	entry, ok := ct.load(key)
+	if !ok {
+		return ct.fetchAndStore(req, base)
+	}
+
+	age := time.Since(entry.StoredAt)
+	ttl, hasTTL := freshnessLifetime(entry.Header)
+
+	if !entry.NoCache && hasTTL && age < ttl {
+		return entry.response(req), nil
+	}
+
+	if !entry.NoCache && hasTTL {
+		if swr, ok := staleWhileRevalidate(entry.Header); ok && age < ttl+swr {
+			// The request's own context may be cancelled the instant the
+			// caller reads this response, so the refresh gets a
+			// detached one instead of inheriting it.
+			bg := req.Clone(context.Background())
+			go ct.refresh(bg, base)
+			return entry.response(req), nil
+		}
+	}
+
+	return ct.revalidate(req, base, key, entry)
+}
+
+func (ct *cacheTransport) fetchAndStore(req *http.Request, base string) (*http.Response, error) {
+	resp, err := ct.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	ct.maybeStore(req, base, resp)
+	return resp, nil
+}
+
+// refresh re-fetches req in the background and replaces the cached entry
+// for it, for stale-while-revalidate hits.
+func (ct *cacheTransport) refresh(req *http.Request, base string) {
+	resp, err := ct.next.RoundTrip(req)
+	if err != nil {
+		return
+	}
+	ct.maybeStore(req, base, resp)
+	resp.Body.Close()
+}
+
+func (ct *cacheTransport) revalidate(req *http.Request, base, key string, entry *cacheEntry) (*http.Response, error) {
+	revReq := cloneRequest(req)
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		revReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		revReq.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := ct.next.RoundTrip(revReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		ct.maybeStore(req, base, resp)
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	entry.StoredAt = time.Now()
+	for k, v := range resp.Header {
+		entry.Header[k] = v
+	}
+	if raw, err := json.Marshal(entry); err == nil {
+		ct.store.Set(key, raw, storeTTLFor(entry.Header))
+	}
+	return entry.response(req), nil
+}
+
+// maybeStore consumes resp.Body (replacing it with a replayable copy) and
+// stores resp under req's cache key if it's cacheable.
+func (ct *cacheTransport) maybeStore(req *http.Request, base string, resp *http.Response) {
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil || !isStorable(req, resp) {
+		return
+	}
+
+	entry := &cacheEntry{
+		StoredAt:   time.Now(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		NoCache:    hasDirective(resp.Header.Get("Cache-Control"), "no-cache"),
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	vary := resp.Header.Values("Vary")
+	ttl := storeTTLFor(resp.Header)
+	ct.store.Set(ct.variantKeyFor(base, req, vary), raw, ttl)
+	if len(vary) > 0 {
+		ct.store.Set(varyIndexKey(base), []byte(strings.Join(vary, ",")), ttl)
+	}
+}
+
+func (ct *cacheTransport) load(key string) (*cacheEntry, bool) {
+	raw, ok := ct.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// variantKey looks up which headers (if any) a cached response for base
+// varies on, and folds the request's values for them into the key.
+func (ct *cacheTransport) variantKey(base string, req *http.Request) string {
+	raw, ok := ct.store.Get(varyIndexKey(base))
+	if !ok || len(raw) == 0 {
+		return base
+	}
+	return ct.variantKeyFor(base, req, strings.Split(string(raw), ","))
+}
+
+func (ct *cacheTransport) variantKeyFor(base string, req *http.Request, vary []string) string {
+	if len(vary) == 0 {
+		return base
+	}
+	parts := make([]string, 0, len(vary))
+	for _, h := range vary {
+		h = strings.TrimSpace(h)
+		parts = append(parts, strings.ToLower(h)+"="+req.Header.Get(h))
+	}
+	sort.Strings(parts)
+	return base + "#" + strings.Join(parts, "&")
+}
+
+func cacheRequestKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+func varyIndexKey(base string) string {
+	return base + "#vary"
+}
+
+// cacheEntry is the serialized form of a cached response.
+type cacheEntry struct {
+	StoredAt   time.Time
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// NoCache records Cache-Control: no-cache, which makes a response
+	// storable but forbids serving it without revalidation.
+	NoCache bool
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusPartialContent:       true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+	http.StatusPermanentRedirect:    true,
+}
+
+func isStorable(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	cc := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if _, ok := cc["private"]; ok {
+		return false
+	}
+	return true
+}
+
+// freshnessLifetime returns how long a response is fresh for, per
+// Cache-Control's s-maxage or max-age, falling back to Expires.
+func freshnessLifetime(header http.Header) (time.Duration, bool) {
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	if v, ok := cc["s-maxage"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if v, ok := cc["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if exp := header.Get("Expires"); exp != "" {
+		if when, err := http.ParseTime(exp); err == nil {
+			return time.Until(when), true
+		}
+	}
+	return 0, false
+}
+
+func staleWhileRevalidate(header http.Header) (time.Duration, bool) {
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	v, ok := cc["stale-while-revalidate"]
+	if !ok {
+		return 0, false
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// storeTTLFor is the hint passed to CacheStore.Set: how long the store
+// can safely forget this entry, covering both its freshness lifetime and
+// any stale-while-revalidate window past it. A response with no
+// freshness information at all (no max-age/s-maxage/Expires) is kept
+// until evicted rather than expired, since the cache still needs it to
+// decide whether to revalidate.
+func storeTTLFor(header http.Header) time.Duration {
+	ttl, hasTTL := freshnessLifetime(header)
+	if !hasTTL {
+		return 0
+	}
+	if swr, ok := staleWhileRevalidate(header); ok {
+		ttl += swr
+	}
+	return ttl
+}
+
+func hasDirective(cacheControl, name string) bool {
+	_, ok := parseCacheControl(cacheControl)[name]
+	return ok
+}
+
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
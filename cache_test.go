@@ -0,0 +1,280 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serves a fresh response from cache without hitting the server again", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("payload"))
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			body, _ := readAndClose(resp)
+			assert.Equal(t, "payload", body)
+		}
+
+		assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("does not cache responses marked Cache-Control: no-store", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("does not cache non-GET/HEAD requests", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Post(server.URL, "text/plain", nil)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("revalidates a stale response and upgrades a 304 to the cached body", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&hits, 1)
+			if n > 1 {
+				assert.Equal(t, `"v1"`, r.Header.Get("If-None-Match"))
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "max-age=0")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("payload"))
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		body, _ := readAndClose(resp)
+		assert.Equal(t, "payload", body)
+
+		resp, err = client.Get(server.URL)
+		require.NoError(t, err)
+		body, _ = readAndClose(resp)
+		assert.Equal(t, "payload", body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("serves a stale-while-revalidate response immediately and refreshes in the background", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		refreshed := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("payload"))
+			if n > 1 {
+				close(refreshed)
+			}
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		readAndClose(resp)
+
+		resp, err = client.Get(server.URL)
+		require.NoError(t, err)
+		body, _ := readAndClose(resp)
+		assert.Equal(t, "payload", body)
+
+		select {
+		case <-refreshed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("background refresh never reached the server")
+		}
+	})
+
+	t.Run("revalidates every use of a no-cache response even within its stale-while-revalidate window", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "no-cache, max-age=60, stale-while-revalidate=30")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("payload"))
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			body, _ := readAndClose(resp)
+			assert.Equal(t, "payload", body)
+		}
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits), "no-cache must be revalidated on every use, even while within max-age and stale-while-revalidate")
+	})
+
+	t.Run("varies cache entries by the Vary header", func(t *testing.T) {
+		t.Parallel()
+
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Vary", "Accept-Language")
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+		}))
+		defer server.Close()
+
+		client := New(WithCache(NewLRUCache(16)))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "en")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		body, _ := readAndClose(resp)
+		assert.Equal(t, "en", body)
+
+		req, err = http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "fr")
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		body, _ = readAndClose(resp)
+		assert.Equal(t, "fr", body)
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+
+		req, err = http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "en")
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		body, _ = readAndClose(resp)
+		assert.Equal(t, "en", body)
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	})
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round-trips a stored value", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewLRUCache(2)
+		store.Set("a", []byte("1"), time.Minute)
+
+		got, ok := store.Get("a")
+		require.True(t, ok)
+		assert.Equal(t, []byte("1"), got)
+	})
+
+	t.Run("evicts the least-recently-used entry once over capacity", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewLRUCache(2)
+		store.Set("a", []byte("1"), time.Minute)
+		store.Set("b", []byte("2"), time.Minute)
+		store.Get("a")
+		store.Set("c", []byte("3"), time.Minute)
+
+		_, ok := store.Get("b")
+		assert.False(t, ok, "b should have been evicted as the least recently used entry")
+		_, ok = store.Get("a")
+		assert.True(t, ok)
+		_, ok = store.Get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("expires an entry past its ttl", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewLRUCache(2)
+		store.Set("a", []byte("1"), time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok := store.Get("a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Delete removes an entry", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewLRUCache(2)
+		store.Set("a", []byte("1"), time.Minute)
+		store.Delete("a")
+
+		_, ok := store.Get("a")
+		assert.False(t, ok)
+	})
+}
+
+func readAndClose(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
@@ -0,0 +1,307 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of performing a round trip when the
+// circuit breaker for the request's host is open.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker open")
+
+// CircuitBreakerState is the state of a single host's circuit.
+type CircuitBreakerState int
+
+const (
+	CircuitClosed CircuitBreakerState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreakerConfig configures the per-host circuit breaker installed
+// by WithCircuitBreaker.
+type CircuitBreakerConfig struct {
+	// WindowBuckets is the number of buckets the rolling window is split
+	// into; WindowBuckets*BucketDuration is the total window length.
+	WindowBuckets int
+	// BucketDuration is the width of a single bucket.
+	BucketDuration time.Duration
+	// MinRequests is the minimum number of requests observed in the
+	// window before the failure ratio is evaluated, so a single failed
+	// request to a quiet host doesn't trip the breaker.
+	MinRequests int
+	// FailureThreshold is the failure ratio, in (0, 1], above which the
+	// circuit opens.
+	FailureThreshold float64
+	// CooldownPeriod is how long the circuit stays open before a single
+	// probe request is let through in the half-open state.
+	CooldownPeriod time.Duration
+	// IsFailure reports whether a round trip counts as a failure. It
+	// defaults to "err != nil or resp.StatusCode >= 500".
+	IsFailure func(*http.Response, error) bool
+	// OnStateChange, if non-nil, is called whenever a host's circuit
+	// transitions between states.
+	OnStateChange func(host string, from, to CircuitBreakerState)
+}
+
+// DefaultCircuitBreakerConfig returns reasonable defaults: a 10 second
+// rolling window in ten 1-second buckets, opening once at least 10
+// requests have been seen and over half of them failed, with a 30 second
+// cooldown before probing again.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		WindowBuckets:    10,
+		BucketDuration:   time.Second,
+		MinRequests:      10,
+		FailureThreshold: 0.5,
+		CooldownPeriod:   30 * time.Second,
+	}
+}
+
+func (cfg CircuitBreakerConfig) isFailure(resp *http.Response, err error) bool {
+	if cfg.IsFailure != nil {
+		return cfg.IsFailure(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp != nil && resp.StatusCode >= http.StatusInternalServerError
+}
+
+func (cfg CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	def := DefaultCircuitBreakerConfig()
+	if cfg.WindowBuckets <= 0 {
+		cfg.WindowBuckets = def.WindowBuckets
+	}
+	if cfg.BucketDuration <= 0 {
+		cfg.BucketDuration = def.BucketDuration
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = def.FailureThreshold
+	}
+	if cfg.CooldownPeriod <= 0 {
+		cfg.CooldownPeriod = def.CooldownPeriod
+	}
+	return cfg
+}
+
+// CircuitBreaker tracks a rolling failure count per host and decides
+// whether a request to that host should be allowed through.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+// NewCircuitBreaker creates a CircuitBreaker, filling in zero-valued
+// fields of cfg from DefaultCircuitBreakerConfig.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		cfg:   cfg.withDefaults(),
+		hosts: make(map[string]*hostCircuit),
+	}
+}
+
+// IsOpen reports whether host's circuit is currently open.
+func (cb *CircuitBreaker) IsOpen(host string) bool {
+	cb.mu.Lock()
+	hc, ok := cb.hosts[host]
+	cb.mu.Unlock()
+	if !ok {
+		return false
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return hc.state == CircuitOpen
+}
+
+func (cb *CircuitBreaker) hostCircuit(host string) *hostCircuit {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	hc, ok := cb.hosts[host]
+	if !ok {
+		hc = newHostCircuit(cb.cfg.WindowBuckets)
+		cb.hosts[host] = hc
+	}
+	return hc
+}
+
+func (cb *CircuitBreaker) notify(host string, from, to CircuitBreakerState) {
+	if cb.cfg.OnStateChange != nil {
+		cb.cfg.OnStateChange(host, from, to)
+	}
+}
+
+// allow reports whether a request to host may proceed, and whether this
+// particular request is the single half-open probe.
+func (cb *CircuitBreaker) allow(host string) (allowed, isProbe bool) {
+	hc := cb.hostCircuit(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	switch hc.state {
+	case CircuitOpen:
+		if time.Since(hc.openedAt) < cb.cfg.CooldownPeriod {
+			return false, false
+		}
+		hc.state = CircuitHalfOpen
+		hc.probing = true
+		cb.notify(host, CircuitOpen, CircuitHalfOpen)
+		return true, true
+	case CircuitHalfOpen:
+		return false, false
+	default:
+		return true, false
+	}
+}
+
+// record reports the outcome of a completed request to host.
+func (cb *CircuitBreaker) record(host string, failed bool) {
+	hc := cb.hostCircuit(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.state == CircuitHalfOpen {
+		hc.probing = false
+		if failed {
+			hc.state = CircuitOpen
+			hc.openedAt = time.Now()
+			cb.notify(host, CircuitHalfOpen, CircuitOpen)
+		} else {
+			hc.state = CircuitClosed
+			hc.reset()
+			cb.notify(host, CircuitHalfOpen, CircuitClosed)
+		}
+		return
+	}
+
+	hc.addSample(failed, cb.cfg.BucketDuration)
+
+	if hc.state == CircuitClosed {
+		total, failures := hc.counts()
+		if total >= cb.cfg.MinRequests && float64(failures)/float64(total) > cb.cfg.FailureThreshold {
+			hc.state = CircuitOpen
+			hc.openedAt = time.Now()
+			cb.notify(host, CircuitClosed, CircuitOpen)
+		}
+	}
+}
+
+// circuitBreakerTransport fails a request fast with ErrCircuitOpen when
+// cb has tripped for its host, instead of sending it through next.
+type circuitBreakerTransport struct {
+	next http.RoundTripper
+	cb   *CircuitBreaker
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	allowed, _ := t.cb.allow(host)
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	t.cb.record(host, t.cb.cfg.isFailure(resp, err))
+	return resp, err
+}
+
+// WithCircuitBreaker wraps the client's transport with a per-host circuit
+// breaker so that requests to a host failing more than cfg's threshold
+// fail fast with ErrCircuitOpen instead of being sent. If WithRetryPolicy
+// is also applied, the breaker always ends up wrapped by the retry loop
+// rather than wrapping it, regardless of which option is applied first,
+// so every retry attempt is checked against (and counted by) the
+// breaker instead of only the overall call.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	return func(c *http.Client) {
+		cb := NewCircuitBreaker(cfg)
+		wrapTransport(c, func(next http.RoundTripper) http.RoundTripper {
+			return &circuitBreakerTransport{next: next, cb: cb}
+		})
+	}
+}
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// hostCircuit is the state machine and rolling window for a single host.
+type hostCircuit struct {
+	mu       sync.Mutex
+	buckets  []bucket
+	state    CircuitBreakerState
+	openedAt time.Time
+	probing  bool
+}
+
+func newHostCircuit(windowBuckets int) *hostCircuit {
+	return &hostCircuit{buckets: make([]bucket, windowBuckets)}
+}
+
+func (hc *hostCircuit) addSample(failed bool, bucketDuration time.Duration) {
+	hc.rotate(bucketDuration)
+	cur := &hc.buckets[len(hc.buckets)-1]
+	if failed {
+		cur.failures++
+	} else {
+		cur.successes++
+	}
+}
+
+// rotate slides the window forward so the last bucket always covers
+// "now", discarding buckets older than the window.
+func (hc *hostCircuit) rotate(bucketDuration time.Duration) {
+	n := len(hc.buckets)
+	if n == 0 {
+		return
+	}
+	now := time.Now()
+	last := &hc.buckets[n-1]
+	if last.start.IsZero() {
+		last.start = now
+		return
+	}
+
+	shift := int(now.Sub(last.start) / bucketDuration)
+	if shift <= 0 {
+		return
+	}
+	if shift >= n {
+		hc.buckets = make([]bucket, n)
+	} else {
+		hc.buckets = append(hc.buckets[shift:], make([]bucket, shift)...)
+	}
+	hc.buckets[n-1].start = now
+}
+
+func (hc *hostCircuit) counts() (total, failures int) {
+	for _, b := range hc.buckets {
+		total += b.successes + b.failures
+		failures += b.failures
+	}
+	return total, failures
+}
+
+func (hc *hostCircuit) reset() {
+	hc.buckets = make([]bucket, len(hc.buckets))
+}
@@ -0,0 +1,229 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("opens after the failure threshold is crossed and IsOpen reflects it", func(t *testing.T) {
+		t.Parallel()
+
+		cb := NewCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      4,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   time.Minute,
+		})
+
+		const host = "example.com"
+		assert.False(t, cb.IsOpen(host))
+
+		cb.record(host, true)
+		cb.record(host, true)
+		cb.record(host, false)
+		assert.False(t, cb.IsOpen(host), "below MinRequests")
+
+		cb.record(host, false)
+		assert.False(t, cb.IsOpen(host), "2/4 failures only meets the 0.5 threshold")
+
+		cb.record(host, true)
+		assert.True(t, cb.IsOpen(host), "3/5 failures is above the 0.5 threshold")
+	})
+
+	t.Run("fails fast with ErrCircuitOpen while open", func(t *testing.T) {
+		t.Parallel()
+
+		cb := NewCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   time.Minute,
+		})
+		cb.record("example.com", true)
+		require.True(t, cb.IsOpen("example.com"))
+
+		allowed, _ := cb.allow("example.com")
+		assert.False(t, allowed)
+	})
+
+	t.Run("half-open allows exactly one probe after cooldown", func(t *testing.T) {
+		t.Parallel()
+
+		cb := NewCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   10 * time.Millisecond,
+		})
+		cb.record("example.com", true)
+		require.True(t, cb.IsOpen("example.com"))
+
+		time.Sleep(20 * time.Millisecond)
+
+		allowed, isProbe := cb.allow("example.com")
+		require.True(t, allowed)
+		require.True(t, isProbe)
+
+		allowed, _ = cb.allow("example.com")
+		assert.False(t, allowed, "a second request during half-open must wait for the probe")
+	})
+
+	t.Run("a successful probe closes the circuit", func(t *testing.T) {
+		t.Parallel()
+
+		cb := NewCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   10 * time.Millisecond,
+		})
+		cb.record("example.com", true)
+		time.Sleep(20 * time.Millisecond)
+		_, _ = cb.allow("example.com")
+		cb.record("example.com", false)
+
+		assert.False(t, cb.IsOpen("example.com"))
+	})
+
+	t.Run("a failed probe reopens the circuit", func(t *testing.T) {
+		t.Parallel()
+
+		var transitions []CircuitBreakerState
+		cb := NewCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   10 * time.Millisecond,
+			OnStateChange: func(host string, from, to CircuitBreakerState) {
+				transitions = append(transitions, to)
+			},
+		})
+		cb.record("example.com", true)
+		time.Sleep(20 * time.Millisecond)
+		_, _ = cb.allow("example.com")
+		cb.record("example.com", true)
+
+		assert.True(t, cb.IsOpen("example.com"))
+		assert.Equal(t, []CircuitBreakerState{CircuitOpen, CircuitHalfOpen, CircuitOpen}, transitions)
+	})
+
+	t.Run("WithCircuitBreaker short-circuits client.Do", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := New(WithCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   time.Minute,
+		}))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		req2, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr = client.Do(req2)
+		require.Error(t, doErr)
+		assert.True(t, errors.Is(doErr, ErrCircuitOpen))
+	})
+
+	t.Run("DoWithRetryContext surfaces ErrCircuitOpen once the circuit is open", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := New(WithCircuitBreaker(CircuitBreakerConfig{
+			WindowBuckets:    1,
+			BucketDuration:   time.Minute,
+			MinRequests:      1,
+			FailureThreshold: 0.5,
+			CooldownPeriod:   time.Minute,
+		}))
+
+		policy := DefaultRetryPolicy()
+		policy.MaxAttempts = 1 // isolate the circuit opening from the retry loop
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr := DoWithRetryContext(context.Background(), client, req, policy)
+		require.Error(t, doErr, "the first request still fails on its own merits (HTTP 500)")
+		require.True(t, client.Transport.(*circuitBreakerTransport).cb.IsOpen(req.URL.Host))
+
+		req2, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr = DoWithRetryContext(context.Background(), client, req2, policy)
+		require.Error(t, doErr)
+		assert.True(t, errors.Is(doErr, ErrCircuitOpen))
+	})
+
+	for _, order := range []string{"retry-then-breaker", "breaker-then-retry"} {
+		order := order
+		t.Run("client.Do caps real requests at the breaker threshold, "+order, func(t *testing.T) {
+			t.Parallel()
+
+			var hits int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = 5
+			policy.BaseDelay = time.Millisecond
+			policy.MaxDelay = time.Millisecond
+
+			breakerCfg := CircuitBreakerConfig{
+				WindowBuckets:    1,
+				BucketDuration:   time.Minute,
+				MinRequests:      2,
+				FailureThreshold: 0.5,
+				CooldownPeriod:   time.Minute,
+			}
+
+			var client *http.Client
+			if order == "retry-then-breaker" {
+				client = New(WithRetryPolicy(policy), WithCircuitBreaker(breakerCfg))
+			} else {
+				client = New(WithCircuitBreaker(breakerCfg), WithRetryPolicy(policy))
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			_, doErr := client.Do(req)
+			require.Error(t, doErr)
+
+			// MinRequests=2 means the breaker opens on the 2nd failed
+			// attempt; attempts 3-5 must be short-circuited, so the
+			// backend should see exactly 2 real requests, not 5.
+			assert.Equal(t, 2, hits, "retry loop must not bypass the breaker once it's open")
+		})
+	}
+}
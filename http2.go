@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// HTTP2Config configures the HTTP/2 transport installed by WithHTTP2.
+type HTTP2Config struct {
+	// StrictMaxConcurrentStreams enforces the server-advertised
+	// SETTINGS_MAX_CONCURRENT_STREAMS limit strictly, queuing requests
+	// that would exceed it instead of opening extra connections. Go's
+	// client-side HTTP/2 transport doesn't support a locally-chosen
+	// stream cap; this is the closest equivalent it exposes.
+	StrictMaxConcurrentStreams bool
+	// ReadIdleTimeout is how long to wait on an idle connection before
+	// sending a health check PING frame. Zero disables health checks.
+	ReadIdleTimeout time.Duration
+	// PingTimeout is how long to wait for a PING response before closing
+	// the connection.
+	PingTimeout time.Duration
+	// AllowHTTP permits using the h2c (HTTP/2 without TLS) scheme for
+	// "http://" URLs, rather than falling back to HTTP/1.1.
+	AllowHTTP bool
+}
+
+// WithHTTP2 reconfigures the client's transport for explicit HTTP/2
+// control. It must be applied before any option that replaces
+// client.Transport with something other than an *http.Transport (such
+// as WithMiddleware, WithRetryPolicy, WithCircuitBreaker, or
+// WithCache): http2.ConfigureTransports needs the live *http.Transport,
+// and if it's no longer there by the time this option runs, WithHTTP2
+// silently has no effect.
+func WithHTTP2(cfg HTTP2Config) Option {
+	return func(c *http.Client) {
+		transport, ok := c.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		h2Transport, err := http2.ConfigureTransports(transport)
+		if err != nil {
+			return
+		}
+
+		h2Transport.StrictMaxConcurrentStreams = cfg.StrictMaxConcurrentStreams
+		h2Transport.ReadIdleTimeout = cfg.ReadIdleTimeout
+		h2Transport.PingTimeout = cfg.PingTimeout
+		h2Transport.AllowHTTP = cfg.AllowHTTP
+	}
+}
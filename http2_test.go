@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP2(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithMaxConnsPerHost sets the transport field", func(t *testing.T) {
+		t.Parallel()
+
+		httpClient := New(WithMaxConnsPerHost(42))
+		transport := httpClient.Transport.(*http.Transport)
+		assert.Equal(t, 42, transport.MaxConnsPerHost)
+	})
+
+	t.Run("WithHTTP2 configures the shared http2.Transport", func(t *testing.T) {
+		t.Parallel()
+
+		httpClient := New(WithHTTP2(HTTP2Config{
+			StrictMaxConcurrentStreams: true,
+			ReadIdleTimeout:            5 * time.Second,
+			PingTimeout:                2 * time.Second,
+			AllowHTTP:                  true,
+		}))
+		require.NotNil(t, httpClient)
+
+		_, ok := httpClient.Transport.(*http.Transport)
+		assert.True(t, ok, "WithHTTP2 upgrades the existing *http.Transport in place rather than replacing it")
+	})
+
+	t.Run("WithHTTP2 has no effect once a prior option has replaced the *http.Transport", func(t *testing.T) {
+		t.Parallel()
+
+		httpClient := New(WithUserAgent("test-agent"), WithHTTP2(HTTP2Config{
+			StrictMaxConcurrentStreams: true,
+		}))
+
+		_, ok := httpClient.Transport.(*http.Transport)
+		assert.False(t, ok, "WithHTTP2 must be applied before any option that wraps client.Transport")
+	})
+}
@@ -0,0 +1,19 @@
+//go:build http3
+
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// WithHTTP3 installs an http3.RoundTripper as the client's transport,
+// speaking HTTP/3 over QUIC instead of TCP. It is only available when
+// built with -tags http3, so the default build doesn't pull in quic-go
+// and its dependency tree.
+func WithHTTP3() Option {
+	return func(c *http.Client) {
+		c.Transport = &http3.RoundTripper{}
+	}
+}
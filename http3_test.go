@@ -0,0 +1,23 @@
+//go:build http3
+
+package httpclient
+
+import (
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP3(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithHTTP3 installs an http3.RoundTripper", func(t *testing.T) {
+		t.Parallel()
+
+		httpClient := New(WithHTTP3())
+		_, ok := httpClient.Transport.(*http3.RoundTripper)
+		assert.True(t, ok)
+	})
+}
@@ -1,8 +1,6 @@
 package httpclient
 
 import (
-	"fmt"
-	"math"
 	"net"
 	"net/http"
 	"net/url"
@@ -80,6 +78,16 @@ func WithMaxIdleConnsPerHost(n int) Option {
 	}
 }
 
+// WithMaxConnsPerHost sets the maximum total connections per host,
+// including both idle and in-use ones. Zero means no limit.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *http.Client) {
+		if transport, ok := c.Transport.(*http.Transport); ok {
+			transport.MaxConnsPerHost = n
+		}
+	}
+}
+
 // WithForceHTTP2Disabled disables HTTP/2
 func WithForceHTTP2Disabled() Option {
 	return func(c *http.Client) {
@@ -158,49 +166,3 @@ func New(opts ...Option) *http.Client {
 
 	return client
 }
-
-// DoWithRetry performs an HTTP request with retries
-func DoWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
-	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		resp, err := client.Do(req)
-		if err == nil {
-			if resp.StatusCode < 400 {
-				return resp, nil
-			}
-			lastErr = fmt.Errorf("unexpected status code %d", resp.StatusCode)
-			resp.Body.Close()
-		} else {
-			lastErr = err
-		}
-
-		// Check context before waiting
-		if req.Context().Err() != nil {
-			return nil, fmt.Errorf("request cancelled or timed out: %w", req.Context().Err())
-		}
-
-		if attempt < maxRetries-1 { // Don't wait on last attempt
-			delay := time.Duration(float64(baseRetryDelay) * math.Pow(2, float64(attempt)))
-			if delay > maxRetryDelay {
-				delay = maxRetryDelay
-			}
-
-			ticker := time.NewTicker(delay)
-			select {
-			case <-ticker.C:
-			case <-req.Context().Done():
-				ticker.Stop()
-				return nil, fmt.Errorf("request cancelled or timed out: %w", req.Context().Err())
-			}
-			ticker.Stop()
-		}
-	}
-	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
-}
-
-func isTemporaryError(err error) bool {
-	if netErr, ok := err.(net.Error); ok {
-		return netErr.Timeout()
-	}
-	return true
-}
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware decorates a RoundTripper with additional behavior, such as
+// logging, tracing, header injection, or auth token refresh.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// WithMiddleware appends mw to the client's transport chain. Middlewares
+// run in the order they're passed to New: the first one wraps the base
+// transport and so is the innermost layer, closest to the wire; each
+// later one wraps everything before it and therefore sees the request
+// first on its way out and the response last on its way back. If
+// WithRetryPolicy is also applied, mw always ends up wrapped by the
+// retry loop rather than wrapping it, regardless of application order,
+// so mw runs on every retry attempt (a bearer token is refreshed per
+// attempt, a span covers one attempt, and so on) instead of once for
+// the whole logical call.
+func WithMiddleware(mw Middleware) Option {
+	return func(c *http.Client) {
+		wrapTransport(c, mw)
+	}
+}
+
+// roundTripperFunc adapts a function to an http.RoundTripper, the way
+// http.HandlerFunc adapts a function to an http.Handler.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithUserAgent sets the User-Agent header on every outgoing request,
+// overriding whatever the request already carries.
+func WithUserAgent(userAgent string) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = cloneRequest(req)
+			req.Header.Set("User-Agent", userAgent)
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+// WithBearerTokenSource sets an Authorization: Bearer header on every
+// outgoing request using a token fetched from source, so callers can
+// plug in OAuth2 refresh, STS, or any other token provider.
+func WithBearerTokenSource(source func(ctx context.Context) (string, error)) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			token, err := source(req.Context())
+			if err != nil {
+				return nil, fmt.Errorf("httpclient: fetching bearer token: %w", err)
+			}
+			req = cloneRequest(req)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+// RequestIDHeader is the header WithRequestIDHeader sets.
+const RequestIDHeader = "X-Request-ID"
+
+// WithRequestIDHeader stamps every outgoing request that doesn't already
+// carry a RequestIDHeader with a random one, so it can be correlated with
+// server-side logs.
+func WithRequestIDHeader() Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(RequestIDHeader) != "" {
+				return next.RoundTrip(req)
+			}
+			id, err := newRequestID()
+			if err != nil {
+				return nil, fmt.Errorf("httpclient: generating request id: %w", err)
+			}
+			req = cloneRequest(req)
+			req.Header.Set(RequestIDHeader, id)
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WithOpenTelemetry wraps every outgoing request in a client span named
+// after its method, tagged with http.method/http.url/http.status_code and
+// marked as errored when the round trip fails or returns a 5xx status.
+func WithOpenTelemetry(tracer trace.Tracer) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), req.Method, trace.WithSpanKind(trace.SpanKindClient))
+			defer span.End()
+
+			span.SetAttributes(
+				attribute.String("http.method", req.Method),
+				attribute.String("http.url", req.URL.String()),
+			)
+
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+
+			span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+			if resp.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, resp.Status)
+			}
+			return resp, nil
+		})
+	})
+}
+
+// cloneRequest returns a shallow copy of req safe to mutate headers on,
+// per the http.RoundTripper contract that RoundTrip must not modify the
+// original request.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := req.Clone(req.Context())
+	return clone
+}
@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithMiddleware composes in the order options are passed to New", func(t *testing.T) {
+		t.Parallel()
+
+		var order []string
+		trace := func(name string) Middleware {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					order = append(order, name)
+					return next.RoundTrip(req)
+				})
+			}
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithMiddleware(trace("inner")), WithMiddleware(trace("outer")))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		assert.Equal(t, []string{"outer", "inner"}, order)
+	})
+
+	t.Run("WithUserAgent overrides the request's User-Agent", func(t *testing.T) {
+		t.Parallel()
+
+		var gotUA string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotUA = r.Header.Get("User-Agent")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithUserAgent("test-agent/1.0"))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "original")
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		assert.Equal(t, "test-agent/1.0", gotUA)
+	})
+
+	t.Run("WithBearerTokenSource sets the Authorization header", func(t *testing.T) {
+		t.Parallel()
+
+		var gotAuth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotAuth = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithBearerTokenSource(func(ctx context.Context) (string, error) {
+			return "secret-token", nil
+		}))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		assert.Equal(t, "Bearer secret-token", gotAuth)
+	})
+
+	t.Run("WithBearerTokenSource surfaces a source error without sending the request", func(t *testing.T) {
+		t.Parallel()
+
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithBearerTokenSource(func(ctx context.Context) (string, error) {
+			return "", errors.New("token unavailable")
+		}))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr := client.Do(req)
+		require.Error(t, doErr)
+		assert.False(t, called)
+	})
+
+	t.Run("WithRequestIDHeader stamps a request id when one isn't already set", func(t *testing.T) {
+		t.Parallel()
+
+		var gotID string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotID = r.Header.Get(RequestIDHeader)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithRequestIDHeader())
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		assert.NotEmpty(t, gotID)
+	})
+
+	t.Run("WithRequestIDHeader leaves an existing request id alone", func(t *testing.T) {
+		t.Parallel()
+
+		var gotID string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotID = r.Header.Get(RequestIDHeader)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithRequestIDHeader())
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "already-set")
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		assert.Equal(t, "already-set", gotID)
+	})
+
+	for _, order := range []string{"retry-then-middleware", "middleware-then-retry"} {
+		order := order
+		t.Run("a middleware runs on every retry attempt, "+order, func(t *testing.T) {
+			t.Parallel()
+
+			var hits int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			var tokenFetches int
+			source := WithBearerTokenSource(func(ctx context.Context) (string, error) {
+				tokenFetches++
+				return "secret-token", nil
+			})
+
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = 3
+			policy.BaseDelay = time.Millisecond
+			policy.MaxDelay = time.Millisecond
+
+			var client *http.Client
+			if order == "retry-then-middleware" {
+				client = New(WithRetryPolicy(policy), source)
+			} else {
+				client = New(source, WithRetryPolicy(policy))
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			_, doErr := client.Do(req)
+			require.Error(t, doErr, "500 is retryable, so the policy exhausts all attempts and surfaces an error")
+
+			assert.Equal(t, 3, hits)
+			assert.Equal(t, 3, tokenFetches, "middleware must run on every retry attempt regardless of option order")
+		})
+	}
+
+	t.Run("WithOpenTelemetry doesn't break a normal request", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithOpenTelemetry(noop.NewTracerProvider().Tracer("test")))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit wraps the client's transport with a token-bucket limiter
+// allowing rps requests per second, with bursts up to burst. Requests
+// block until a token is available or their context is cancelled. If
+// WithRetryPolicy is also applied, the limiter always ends up wrapped
+// by the retry loop rather than wrapping it, regardless of application
+// order, so every retry attempt takes its own token instead of one
+// token covering a whole logical call.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *http.Client) {
+		limiter := rate.NewLimiter(rate.Limit(rps), burst)
+		wrapTransport(c, func(next http.RoundTripper) http.RoundTripper {
+			return &rateLimitTransport{next: next, limiter: limiter}
+		})
+	}
+}
+
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// WithMaxInFlight wraps the client's transport with a semaphore allowing
+// at most n requests in flight at once. Requests beyond that block until
+// a slot frees up or their context is cancelled. If WithRetryPolicy is
+// also applied, the semaphore always ends up wrapped by the retry loop
+// rather than wrapping it, regardless of application order, so every
+// retry attempt holds its own slot instead of one slot covering a whole
+// logical call.
+func WithMaxInFlight(n int) Option {
+	return func(c *http.Client) {
+		sem := make(chan struct{}, n)
+		wrapTransport(c, func(next http.RoundTripper) http.RoundTripper {
+			return &maxInFlightTransport{next: next, sem: sem}
+		})
+	}
+}
+
+type maxInFlightTransport struct {
+	next http.RoundTripper
+	sem  chan struct{}
+}
+
+func (t *maxInFlightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.sem }()
+
+	return t.next.RoundTrip(req)
+}
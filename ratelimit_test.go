@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithRateLimit paces requests to the configured rps", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithRateLimit(50, 1))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			resp, doErr := client.Do(req)
+			require.NoError(t, doErr)
+			resp.Body.Close()
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("WithRateLimit returns when the context is cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithRateLimit(1, 1))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		resp.Body.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req2, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr = client.Do(req2)
+		require.Error(t, doErr)
+	})
+
+	for _, order := range []string{"retry-then-ratelimit", "ratelimit-then-retry"} {
+		order := order
+		t.Run("every retry attempt takes its own token, "+order, func(t *testing.T) {
+			t.Parallel()
+
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = 3
+			policy.BaseDelay = time.Millisecond
+			policy.MaxDelay = time.Millisecond
+
+			// A single-token, no-refill bucket: once the first attempt
+			// has taken the token, every later Wait fails, so only one
+			// request reaches the server. If the limiter only gated one
+			// call per logical client.Do (instead of one per retry
+			// attempt), all 3 attempts would reach it.
+			limiter := WithRateLimit(0, 1)
+
+			var client *http.Client
+			if order == "retry-then-ratelimit" {
+				client = New(WithRetryPolicy(policy), limiter)
+			} else {
+				client = New(limiter, WithRetryPolicy(policy))
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(req.Context(), 100*time.Millisecond)
+			defer cancel()
+			_, doErr := client.Do(req.WithContext(ctx))
+			require.Error(t, doErr)
+
+			assert.EqualValues(t, 1, atomic.LoadInt32(&hits), "only the first attempt should get a token before the limiter starves the rest")
+		})
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithMaxInFlight bounds concurrent requests", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		inFlight, peak := 0, 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New(WithMaxInFlight(2))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+				if err != nil {
+					return
+				}
+				resp, doErr := client.Do(req)
+				if doErr == nil {
+					resp.Body.Close()
+				}
+			}()
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.LessOrEqual(t, peak, 2)
+	})
+
+	t.Run("WithMaxInFlight returns when the context is cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		block := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		defer close(block)
+
+		client := New(WithMaxInFlight(1))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		go func() {
+			resp, doErr := client.Do(req)
+			if doErr == nil {
+				resp.Body.Close()
+			}
+		}()
+		time.Sleep(10 * time.Millisecond) // let it take the only slot
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req2, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, doErr := client.Do(req2)
+		require.Error(t, doErr)
+	})
+}
@@ -0,0 +1,245 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how DoWithRetryContext retries a request: how many
+// attempts to make, how long to wait between them, and which failures are
+// worth retrying at all.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// BaseDelay is the initial backoff delay before jitter is applied.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff delay.
+	MaxDelay time.Duration
+	// RetryableStatus is the set of HTTP status codes that should be
+	// retried. A nil/empty set falls back to DefaultRetryableStatus.
+	RetryableStatus map[int]bool
+	// RetryableError reports whether err is worth retrying. A nil func
+	// falls back to isTemporaryError.
+	RetryableError func(error) bool
+}
+
+// DefaultRetryableStatus returns the status codes retried when a
+// RetryPolicy doesn't specify its own set.
+func DefaultRetryableStatus() map[int]bool {
+	return map[int]bool{
+		http.StatusRequestTimeout:      true, // 408
+		425:                            true, // Too Early
+		http.StatusTooManyRequests:     true, // 429
+		http.StatusInternalServerError: true, // 500
+		http.StatusBadGateway:          true, // 502
+		http.StatusServiceUnavailable:  true, // 503
+		http.StatusGatewayTimeout:      true, // 504
+	}
+}
+
+// DefaultRetryPolicy is the policy DoWithRetry uses: maxRetries attempts,
+// full-jitter exponential backoff from baseRetryDelay capped at
+// maxRetryDelay, retrying the default status set and temporary errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     maxRetries,
+		BaseDelay:       baseRetryDelay,
+		MaxDelay:        maxRetryDelay,
+		RetryableStatus: DefaultRetryableStatus(),
+		RetryableError:  isTemporaryError,
+	}
+}
+
+func (p RetryPolicy) retryableStatus() map[int]bool {
+	if len(p.RetryableStatus) > 0 {
+		return p.RetryableStatus
+	}
+	return DefaultRetryableStatus()
+}
+
+func (p RetryPolicy) retryableError(err error) bool {
+	if p.RetryableError != nil {
+		return p.RetryableError(err)
+	}
+	return isTemporaryError(err)
+}
+
+// WithRetryPolicy wraps the client's transport so that every request sent
+// through it is retried according to policy. If WithCircuitBreaker is
+// also applied, the retry loop always ends up as the outermost layer
+// with the breaker beneath it, regardless of application order, so
+// retries still go through the breaker on every attempt instead of
+// bypassing it after the first.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *http.Client) {
+		c.Transport = &retryTransport{
+			next:   transportOrDefault(c.Transport),
+			policy: policy,
+		}
+	}
+}
+
+func transportOrDefault(t http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return http.DefaultTransport
+	}
+	return t
+}
+
+// wrapTransport installs wrap as the client's transport. If a
+// retryTransport is already installed, wrap is inserted beneath it
+// instead of on top, so the retry loop keeps wrapping it and every
+// retry attempt still observes wrap's effects (another token taken,
+// another slot held, another circuit check, another middleware pass),
+// regardless of the order WithRetryPolicy and the other option are
+// applied in. Every option that wraps client.Transport should install
+// itself through this helper rather than assigning c.Transport
+// directly, so its composition with retries stays order-independent.
+func wrapTransport(c *http.Client, wrap func(http.RoundTripper) http.RoundTripper) {
+	if rt, ok := c.Transport.(*retryTransport); ok {
+		rt.next = wrap(transportOrDefault(rt.next))
+		return
+	}
+	c.Transport = wrap(transportOrDefault(c.Transport))
+}
+
+// retryTransport retries a single round trip according to policy. It lets
+// callers get automatic retries from client.Do without going through
+// DoWithRetry.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return retryLoop(req.Context(), rt.next.RoundTrip, req, rt.policy)
+}
+
+// DoWithRetry performs an HTTP request with retries, using
+// DefaultRetryPolicy.
+func DoWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	return DoWithRetryContext(req.Context(), client, req, DefaultRetryPolicy())
+}
+
+// DoWithRetryContext performs req with client, retrying according to policy
+// until it succeeds, policy is exhausted, or ctx is done.
+func DoWithRetryContext(ctx context.Context, client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	return retryLoop(ctx, client.Do, req, policy)
+}
+
+func retryLoop(ctx context.Context, do func(*http.Request) (*http.Response, error), req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := rewindBody(req); err != nil {
+				if err == errBodyNotReplayable {
+					return nil, lastErr
+				}
+				return nil, err
+			}
+		}
+
+		resp, err := do(req)
+		if err == nil {
+			if !policy.retryableStatus()[resp.StatusCode] {
+				return resp, nil
+			}
+			lastErr = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		} else {
+			if !policy.retryableError(err) {
+				return nil, err
+			}
+			lastErr = err
+		}
+
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, fmt.Errorf("request cancelled or timed out: %w", ctx.Err())
+		}
+
+		if attempt < policy.MaxAttempts-1 { // don't wait on the last attempt
+			delay := retryAfter(resp)
+			if delay <= 0 {
+				delay = backoffWithFullJitter(policy.BaseDelay, policy.MaxDelay, attempt)
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("request cancelled or timed out: %w", ctx.Err())
+			}
+			timer.Stop()
+		} else if resp != nil {
+			resp.Body.Close()
+		}
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", policy.MaxAttempts, lastErr)
+}
+
+// backoffWithFullJitter computes min(maxDelay, baseDelay*2^attempt) and
+// then picks a random delay in [0, that), following AWS's "full jitter"
+// algorithm: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func backoffWithFullJitter(baseDelay, maxDelay time.Duration, attempt int) time.Duration {
+	delayCap := float64(maxDelay)
+	backoff := float64(baseDelay) * math.Pow(2, float64(attempt))
+	if backoff > delayCap {
+		backoff = delayCap
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// retryAfter returns the delay requested by a response's Retry-After
+// header, or 0 if the response has none or it can't be parsed.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isTemporaryError reports whether err is worth retrying. Context
+// cancellation and deadline errors are terminal: retrying them can't
+// possibly succeed. An open circuit breaker is also terminal: hammering a
+// host's breaker with retries defeats the point of having one.
+func isTemporaryError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxRetryableBodyBytes bounds how much of an io.Reader body
+// NewRetryableRequest will buffer into memory so it can be replayed on
+// retry. Bodies larger than this fail fast instead of silently buffering
+// an unbounded stream. It can be lowered or raised per process.
+var MaxRetryableBodyBytes int64 = 10 << 20 // 10MiB
+
+// errBodyNotReplayable signals that a request body was already consumed
+// and has no GetBody to rewind it; the caller should give up and surface
+// the error from the attempt that consumed it, not retry blindly.
+var errBodyNotReplayable = errors.New("httpclient: request body already read and not replayable")
+
+// NewRetryableRequest builds an *http.Request whose body can be replayed
+// by DoWithRetry/DoWithRetryContext. body may be nil, []byte, string,
+// io.ReadSeeker, or a plain io.Reader (buffered into memory, up to
+// MaxRetryableBodyBytes, so it can be rewound between attempts).
+func NewRetryableRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	if body == nil {
+		return http.NewRequestWithContext(ctx, method, url, nil)
+	}
+
+	switch b := body.(type) {
+	case []byte:
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+		return req, nil
+	case string:
+		return NewRetryableRequest(ctx, method, url, []byte(b))
+	case io.ReadSeeker:
+		offset, err := b.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("httpclient: determining body offset: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, io.NopCloser(b))
+		if err != nil {
+			return nil, err
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := b.Seek(offset, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("httpclient: rewinding body: %w", err)
+			}
+			return io.NopCloser(b), nil
+		}
+		return req, nil
+	case io.Reader:
+		buf, err := bufferBody(b, MaxRetryableBodyBytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewRetryableRequest(ctx, method, url, buf)
+	default:
+		return nil, fmt.Errorf("httpclient: unsupported body type %T", body)
+	}
+}
+
+func bufferBody(r io.Reader, max int64) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: buffering request body: %w", err)
+	}
+	if int64(len(buf)) > max {
+		return nil, fmt.Errorf("httpclient: request body exceeds max retryable buffer size of %d bytes", max)
+	}
+	return buf, nil
+}
+
+// rewindBody resets req.Body ahead of a retry attempt using req.GetBody,
+// the standard net/http hook populated by http.NewRequest for
+// *bytes.Buffer, *bytes.Reader, and *strings.Reader bodies (and by NewRetryableRequest for
+// everything else). If the request has a body but no way to rewind it,
+// it returns errBodyNotReplayable so the caller can give up on the retry
+// instead of resending a partially- or fully-drained body.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return errBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("httpclient: rewinding body for retry: %w", err)
+	}
+	req.Body = body
+	return nil
+}
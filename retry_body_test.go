@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryableRequest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("byte slice body is replayable", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", []byte("payload"))
+		require.NoError(t, err)
+		require.NotNil(t, req.GetBody)
+
+		first, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(first))
+
+		rewound, err := req.GetBody()
+		require.NoError(t, err)
+		second, err := io.ReadAll(rewound)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(second))
+	})
+
+	t.Run("string body is replayable", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", "payload")
+		require.NoError(t, err)
+		require.NotNil(t, req.GetBody)
+	})
+
+	t.Run("io.ReadSeeker body rewinds to its starting offset", func(t *testing.T) {
+		t.Parallel()
+
+		seeker := bytes.NewReader([]byte("payload"))
+		req, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", seeker)
+		require.NoError(t, err)
+
+		rewound, err := req.GetBody()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rewound)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(data))
+	})
+
+	t.Run("plain io.Reader is buffered so it can be replayed", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", struct{ io.Reader }{strings.NewReader("payload")})
+		require.NoError(t, err)
+		require.NotNil(t, req.GetBody)
+
+		rewound, err := req.GetBody()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rewound)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(data))
+	})
+
+	t.Run("io.Reader over the buffer limit fails fast", func(t *testing.T) {
+		t.Parallel()
+
+		old := MaxRetryableBodyBytes
+		MaxRetryableBodyBytes = 4
+		defer func() { MaxRetryableBodyBytes = old }()
+
+		_, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", struct{ io.Reader }{strings.NewReader("payload")})
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported body type is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewRetryableRequest(context.Background(), http.MethodPost, "http://example.com", 42)
+		require.Error(t, err)
+	})
+}
+
+func TestDoWithRetryContextBodyReplay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replays the body on every retry attempt", func(t *testing.T) {
+		t.Parallel()
+
+		var gotBodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			gotBodies = append(gotBodies, string(body))
+			if len(gotBodies) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		req, err := NewRetryableRequest(context.Background(), http.MethodPost, server.URL, []byte("payload"))
+		require.NoError(t, err)
+
+		policy := DefaultRetryPolicy()
+		policy.BaseDelay = time.Millisecond
+		policy.MaxDelay = 10 * time.Millisecond
+
+		resp, doErr := DoWithRetryContext(context.Background(), New(), req, policy)
+		require.NoError(t, doErr)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"payload", "payload", "payload"}, gotBodies)
+	})
+
+	t.Run("gives up instead of retrying a non-replayable body", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("payload")))
+		require.NoError(t, err)
+		req.GetBody = nil
+
+		_, doErr := DoWithRetryContext(context.Background(), New(), req, DefaultRetryPolicy())
+		require.Error(t, doErr)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,176 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("DoWithRetryContext succeeds without retrying non-retryable status", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := New()
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, doErr := DoWithRetryContext(context.Background(), client, req, DefaultRetryPolicy())
+		require.NoError(t, doErr)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("DoWithRetryContext retries retryable status until success", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New()
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		policy := DefaultRetryPolicy()
+		policy.BaseDelay = time.Millisecond
+		policy.MaxDelay = 10 * time.Millisecond
+
+		resp, doErr := DoWithRetryContext(context.Background(), client, req, policy)
+		require.NoError(t, doErr)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("DoWithRetryContext honors Retry-After delta-seconds", func(t *testing.T) {
+		t.Parallel()
+
+		var first time.Time
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if first.IsZero() {
+				first = time.Now()
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := New()
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, doErr := DoWithRetryContext(context.Background(), client, req, DefaultRetryPolicy())
+		require.NoError(t, doErr)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(first), 1*time.Second)
+	})
+
+	t.Run("DoWithRetryContext stops retrying a non-retryable error", func(t *testing.T) {
+		t.Parallel()
+
+		client := New(WithTimeout(time.Second))
+		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:0", nil)
+		require.NoError(t, err)
+
+		policy := DefaultRetryPolicy()
+		policy.RetryableError = func(error) bool { return false }
+
+		_, doErr := DoWithRetryContext(context.Background(), client, req, policy)
+		require.Error(t, doErr)
+	})
+
+	t.Run("WithRetryPolicy retries transparently through client.Do", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts < 2 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		policy := DefaultRetryPolicy()
+		policy.BaseDelay = time.Millisecond
+		policy.MaxDelay = 10 * time.Millisecond
+
+		client := New(WithRetryPolicy(policy))
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, doErr := client.Do(req)
+		require.NoError(t, doErr)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("isTemporaryError treats context errors as terminal", func(t *testing.T) {
+		t.Parallel()
+
+		assert.False(t, isTemporaryError(context.Canceled))
+		assert.False(t, isTemporaryError(context.DeadlineExceeded))
+		assert.True(t, isTemporaryError(errors.New("boom")))
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("delta-seconds", func(t *testing.T) {
+		t.Parallel()
+
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+		assert.Equal(t, 2*time.Second, retryAfter(resp))
+	})
+
+	t.Run("HTTP-date", func(t *testing.T) {
+		t.Parallel()
+
+		when := time.Now().Add(3 * time.Second).UTC()
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{when.Format(http.TimeFormat)}}}
+		d := retryAfter(resp)
+		assert.Greater(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, 3*time.Second)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		t.Parallel()
+
+		resp := &http.Response{Header: http.Header{}}
+		assert.Equal(t, time.Duration(0), retryAfter(resp))
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		t.Parallel()
+
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"not-a-value-" + strconv.Itoa(0)}}}
+		assert.Equal(t, time.Duration(0), retryAfter(resp))
+	})
+}